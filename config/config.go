@@ -0,0 +1,245 @@
+// Package config builds gowebapp's runtime configuration from a YAML file
+// plus GOWEBAPP_* environment overrides, 12-factor style, so one binary can
+// run unmodified in dev, staging, and production.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Server holds every setting needed to bring up gowebapp's HTTP, TLS, CORS,
+// CSRF, database, logging, and metrics layers.
+type Server struct {
+	ListenAddr      string        `yaml:"listen_addr"`
+	TLSEnabled      bool          `yaml:"tls_enabled"`
+	CertCacheDir    string        `yaml:"cert_cache_dir"`
+	Domains         []string      `yaml:"domains"`
+	BaseURL         string        `yaml:"base_url"`
+	TimeZone        string        `yaml:"time_zone"`
+	GracefulTimeout time.Duration `yaml:"graceful_timeout"`
+
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+	CORSAllowedMethods []string `yaml:"cors_allowed_methods"`
+	CORSAllowedHeaders []string `yaml:"cors_allowed_headers"`
+
+	CSRFSecure         bool     `yaml:"csrf_secure"`
+	CSRFTrustedOrigins []string `yaml:"csrf_trusted_origins"`
+	CSRFSecretKey      string   `yaml:"csrf_secret_key"`
+
+	DBDSN              string `yaml:"db_dsn"`
+	TokenEncryptionKey string `yaml:"token_encryption_key"`
+
+	LogFormat   string `yaml:"log_format"`
+	LogLevel    string `yaml:"log_level"`
+	MetricsAddr string `yaml:"metrics_addr"`
+
+	RateLimitRPS       float64       `yaml:"rate_limit_rps"`
+	RateLimitBurst     int           `yaml:"rate_limit_burst"`
+	LoginMaxFailures   int           `yaml:"login_max_failures"`
+	LoginWindow        time.Duration `yaml:"login_window"`
+	LoginLockout       time.Duration `yaml:"login_lockout"`
+	LoginPaths         []string      `yaml:"login_paths"`
+	RateLimitRedisAddr string        `yaml:"rate_limit_redis_addr"`
+
+	// LoginFailureMethods and LoginFailureStatusCodes are the request method
+	// and response status that mark a failed login on a LoginPaths route,
+	// i.e. yabi's auth routes: POST only, 401 on invalid credentials.
+	LoginFailureMethods     []string `yaml:"login_failure_methods"`
+	LoginFailureStatusCodes []int    `yaml:"login_failure_status_codes"`
+}
+
+// defaultServer returns gowebapp's development defaults. Load overrides
+// these with whatever is present in the config file and environment.
+func defaultServer() Server {
+	return Server{
+		ListenAddr:              "127.0.0.1:8081",
+		TimeZone:                "Asia/Manila",
+		GracefulTimeout:         15 * time.Second,
+		CORSAllowedOrigins:      []string{"*"},
+		CORSAllowedMethods:      []string{"GET", "POST", "PUT", "HEAD", "OPTIONS"},
+		CORSAllowedHeaders:      []string{"X-Requested-With", "Content-Type", "Authorization"},
+		CSRFTrustedOrigins:      []string{"*"},
+		LogFormat:               "text",
+		LogLevel:                "info",
+		MetricsAddr:             "127.0.0.1:9090",
+		RateLimitRPS:            5,
+		RateLimitBurst:          10,
+		LoginMaxFailures:        5,
+		LoginWindow:             10 * time.Minute,
+		LoginLockout:            15 * time.Minute,
+		LoginPaths:              []string{"/login", "/password-reset"},
+		LoginFailureMethods:     []string{"POST"},
+		LoginFailureStatusCodes: []int{401},
+	}
+}
+
+// Load builds a Server from path (a YAML file, optional) and then applies
+// any GOWEBAPP_* environment overrides on top.
+func Load(path string) (*Server, error) {
+	s := defaultServer()
+
+	if path != "" {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+	}
+
+	applyEnvOverrides(&s)
+	return &s, nil
+}
+
+// Validate rejects a Server that would boot into a silently insecure or
+// broken state: an empty CSRF key produces a predictable HMAC key, an empty
+// DSN only fails once the first query runs, and TLS with no Domains accepts
+// a certificate for any host that asks.
+func (s *Server) Validate() error {
+	var problems []string
+	if s.CSRFSecretKey == "" {
+		problems = append(problems, "csrf_secret_key must not be empty")
+	}
+	if s.DBDSN == "" {
+		problems = append(problems, "db_dsn must not be empty")
+	}
+	if s.TLSEnabled && len(s.Domains) == 0 {
+		problems = append(problems, "domains must not be empty when tls_enabled is true")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// applyEnvOverrides mutates s with whichever GOWEBAPP_* variables are set,
+// e.g. GOWEBAPP_LISTEN_ADDR overrides listen_addr.
+func applyEnvOverrides(s *Server) {
+	if v, ok := os.LookupEnv("GOWEBAPP_LISTEN_ADDR"); ok {
+		s.ListenAddr = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_TLS_ENABLED"); ok {
+		s.TLSEnabled, _ = strconv.ParseBool(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CERT_CACHE_DIR"); ok {
+		s.CertCacheDir = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_DOMAINS"); ok {
+		s.Domains = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_BASE_URL"); ok {
+		s.BaseURL = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_TIME_ZONE"); ok {
+		s.TimeZone = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_GRACEFUL_TIMEOUT"); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.GracefulTimeout = d
+		}
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CORS_ALLOWED_ORIGINS"); ok {
+		s.CORSAllowedOrigins = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CORS_ALLOWED_METHODS"); ok {
+		s.CORSAllowedMethods = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CORS_ALLOWED_HEADERS"); ok {
+		s.CORSAllowedHeaders = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CSRF_SECURE"); ok {
+		s.CSRFSecure, _ = strconv.ParseBool(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CSRF_TRUSTED_ORIGINS"); ok {
+		s.CSRFTrustedOrigins = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_CSRF_SECRET_KEY"); ok {
+		s.CSRFSecretKey = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_DB_DSN"); ok {
+		s.DBDSN = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_TOKEN_ENCRYPTION_KEY"); ok {
+		s.TokenEncryptionKey = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOG_FORMAT"); ok {
+		s.LogFormat = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOG_LEVEL"); ok {
+		s.LogLevel = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_METRICS_ADDR"); ok {
+		s.MetricsAddr = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_RATE_LIMIT_RPS"); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			s.RateLimitRPS = f
+		}
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_RATE_LIMIT_BURST"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			s.RateLimitBurst = n
+		}
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOGIN_MAX_FAILURES"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			s.LoginMaxFailures = n
+		}
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOGIN_WINDOW"); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.LoginWindow = d
+		}
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOGIN_LOCKOUT"); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.LoginLockout = d
+		}
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOGIN_PATHS"); ok {
+		s.LoginPaths = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_RATE_LIMIT_REDIS_ADDR"); ok {
+		s.RateLimitRedisAddr = v
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOGIN_FAILURE_METHODS"); ok {
+		s.LoginFailureMethods = splitCSV(v)
+	}
+	if v, ok := os.LookupEnv("GOWEBAPP_LOGIN_FAILURE_STATUS_CODES"); ok {
+		if codes, err := splitCSVInts(v); err == nil {
+			s.LoginFailureStatusCodes = codes
+		}
+	}
+}
+
+// splitCSV splits a comma-separated environment value into a trimmed slice.
+func splitCSV(v string) []string {
+	parts := strings.Split(v, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// splitCSVInts splits a comma-separated environment value into ints, e.g.
+// for GOWEBAPP_LOGIN_FAILURE_STATUS_CODES="401,403".
+func splitCSVInts(v string) ([]int, error) {
+	parts := splitCSV(v)
+	ints := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = n
+	}
+	return ints, nil
+}
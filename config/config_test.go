@@ -0,0 +1,120 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadAppliesDefaultsWithNoFileOrEnv(t *testing.T) {
+	s, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s.ListenAddr != "127.0.0.1:8081" {
+		t.Errorf("ListenAddr = %q, want default", s.ListenAddr)
+	}
+	if s.RateLimitRPS != 5 {
+		t.Errorf("RateLimitRPS = %v, want default 5", s.RateLimitRPS)
+	}
+}
+
+func TestEnvOverridesTakePrecedenceOverDefaults(t *testing.T) {
+	t.Setenv("GOWEBAPP_LISTEN_ADDR", "0.0.0.0:9000")
+	t.Setenv("GOWEBAPP_RATE_LIMIT_RPS", "42.5")
+	t.Setenv("GOWEBAPP_LOGIN_PATHS", "/login, /password-reset, /mfa")
+	t.Setenv("GOWEBAPP_LOGIN_FAILURE_STATUS_CODES", "401, 403")
+	t.Setenv("GOWEBAPP_GRACEFUL_TIMEOUT", "30s")
+
+	s, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s.ListenAddr != "0.0.0.0:9000" {
+		t.Errorf("ListenAddr = %q, want env override", s.ListenAddr)
+	}
+	if s.RateLimitRPS != 42.5 {
+		t.Errorf("RateLimitRPS = %v, want 42.5", s.RateLimitRPS)
+	}
+	if got, want := s.LoginPaths, []string{"/login", "/password-reset", "/mfa"}; !stringSlicesEqual(got, want) {
+		t.Errorf("LoginPaths = %v, want %v", got, want)
+	}
+	if got, want := s.LoginFailureStatusCodes, []int{401, 403}; !intSlicesEqual(got, want) {
+		t.Errorf("LoginFailureStatusCodes = %v, want %v", got, want)
+	}
+	if s.GracefulTimeout != 30*time.Second {
+		t.Errorf("GracefulTimeout = %v, want 30s", s.GracefulTimeout)
+	}
+}
+
+func TestEnvOverrideIgnoredWhenUnparsable(t *testing.T) {
+	t.Setenv("GOWEBAPP_LOGIN_FAILURE_STATUS_CODES", "not-a-number")
+
+	s, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, want := s.LoginFailureStatusCodes, []int{401}; !intSlicesEqual(got, want) {
+		t.Errorf("LoginFailureStatusCodes = %v, want unchanged default %v", got, want)
+	}
+}
+
+func TestSplitCSVTrimsWhitespace(t *testing.T) {
+	got := splitCSV(" a, b ,c")
+	want := []string{"a", "b", "c"}
+	if !stringSlicesEqual(got, want) {
+		t.Errorf("splitCSV = %v, want %v", got, want)
+	}
+}
+
+func TestValidateRejectsMissingRequiredFields(t *testing.T) {
+	s := defaultServer()
+	if err := s.Validate(); err == nil {
+		t.Fatal("expected Validate to reject empty csrf_secret_key/db_dsn")
+	}
+
+	s.CSRFSecretKey = "a-real-secret"
+	s.DBDSN = "user:pass@tcp(127.0.0.1:3306)/db"
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestValidateRejectsTLSWithNoDomains(t *testing.T) {
+	s := defaultServer()
+	s.CSRFSecretKey = "a-real-secret"
+	s.DBDSN = "user:pass@tcp(127.0.0.1:3306)/db"
+	s.TLSEnabled = true
+
+	if err := s.Validate(); err == nil {
+		t.Fatal("expected Validate to reject tls_enabled with no domains")
+	}
+
+	s.Domains = []string{"example.com"}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func intSlicesEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,171 +1,319 @@
-package main
-
-import (
-	"context"
-	"database/sql"
-	"flag"
-	"fmt"
-	"gowebapp/api"
-	"gowebapp/config"
-	"gowebapp/yabi"
-	"net/http"
-	"os"
-	"os/signal"
-	"time"
-
-	"github.com/gorilla/csrf"
-	"github.com/gorilla/handlers"
-	"github.com/gorilla/mux"
-	"github.com/itrepablik/itrlog"
-	"github.com/itrepablik/sakto"
-
-	_ "github.com/go-sql-driver/mysql"
-)
-
-// CurrentLocalTime gets the local server time with corresponding timezone
-var CurrentLocalTime = sakto.GetCurDT(time.Now(), "Asia/Manila")
-
-// IsProdServerMode server mode indicator, make it true to switch to production server settings
-var IsProdServerMode bool = false // true
-
-func init() {
-	// Custom settings to initialize the itrlog.
-	itrlog.SetLogInit(50, 90, "logs_gowebapp", "")
-	// This is for the github changes test only
-}
-
-func main() {
-	fmt.Println("Hello, WebAssembly!")
-	os.Setenv("TZ", config.SiteTimeZone) // Set the local timezone globally
-	fmt.Println("Starting the web servers at ", CurrentLocalTime)
-
-	var dir string
-	var wait time.Duration
-
-	// dir value for localhost Windows OS must be "static", otherwise, "." for Linux OS
-	flag.DurationVar(&wait, "graceful-timeout", time.Second*15, "the duration for which the server gracefully wait for existing connections to finish - e.g. 15s or 1m")
-	flag.StringVar(&dir, "dir", "static", "the directory to serve files from. Defaults to the current dir")
-	flag.Parse()
-
-	r := mux.NewRouter()
-
-	// Create cross-site request forgery (CSRF) protection in every http requests.
-	// 32-byte-long-auth-key []string{config.SiteDomainName}
-
-	// Default is development settings
-	webServerIP := "127.0.0.1:8081" // default to dev localhost
-
-	csrfMiddleware := csrf.Protect(
-		[]byte(config.SecretKeyCORS),
-		csrf.Secure(false),                 // Make this to 'false' only for local dev, if not HTTPS, don't make this as 'true'
-		csrf.TrustedOrigins([]string{"*"}), // for dev only
-	)
-
-	// This is related to the CORS config to allow all origins []string{"*"} or specify only allowed IP or hostname.
-	cors := handlers.CORS(
-		handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}),
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS"}),
-		handlers.AllowedOrigins([]string{"*"}), // for dev only
-	)
-
-	// This will be overwritten when the IsProdServerMode = true
-	if IsProdServerMode {
-		csrfMiddleware = csrf.Protect(
-			[]byte(config.SecretKeyCORS),
-			csrf.Secure(true),                                    // Make this to 'false' only for local dev, if not HTTPS, don't make this as 'true'
-			csrf.TrustedOrigins([]string{config.SiteDomainName}), // for production only
-		)
-		// This is related to the CORS config to allow all origins []string{"*"} or specify only allowed IP or hostname.
-		cors = handlers.CORS(
-			handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}),
-			handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS"}),
-			handlers.AllowedOrigins([]string{config.SiteDomainName}), // for production only
-		)
-		webServerIP = "139.162.59.254:8081" // prod only
-	}
-
-	r.Use(cors)
-	r.Use(csrfMiddleware)
-	r.Use(loggingMiddleware)
-	r.Use(mux.CORSMethodMiddleware(r))
-
-	// This will serve the files under http://localhost:8000/static/<filename>
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(dir))))
-
-	// Initialize the APIs here
-	api.MainRouters(r)      // URLs for the main app.
-	api.AuthRouters(r)      // URLs for the auth app.
-	api.DashboardRouters(r) // URLs for the auth app.
-
-	// Initialize the Yabi auth API here
-	yabiBaseURL := "http://" + webServerIP + "/" // default to dev localhost
-	if IsProdServerMode {
-		yabiBaseURL = config.SiteBaseURLProd
-	}
-	yabi.SetYabiConfig(&yabi.InitYabi{
-		BaseURL:                yabiBaseURL,
-		DBConStr:               api.DBConStr(""),
-		AutoRemoveExpiredToken: 5,
-	})
-
-	// Initializes the http server
-	srv := &http.Server{
-		Addr: webServerIP,
-		// Good practice to set timeouts to avoid Slowloris attacks.
-		WriteTimeout: time.Second * 15,
-		ReadTimeout:  time.Second * 15,
-		IdleTimeout:  time.Second * 60,
-		Handler:      r, // Pass our instance of gorilla/mux in.
-	}
-
-	// Initialize the MySQL server connection
-	// Open the MySQL DSB Connection
-	dbYabi, err := sql.Open("mysql", api.DBConStr(""))
-	if err != nil {
-		itrlog.Error(err)
-	}
-	defer dbYabi.Close()
-
-	// Run our server in a goroutine so that it doesn't block.
-	go func() {
-		msg := `Web server started at `
-		fmt.Println(msg, CurrentLocalTime)
-		itrlog.Info("Web server started at ", CurrentLocalTime)
-
-		yabi.RestoreToken(dbYabi, config.MyEncryptDecryptSK) // Restore the active yabi tokens
-
-		if err := srv.ListenAndServe(); err != nil {
-			itrlog.Error(err)
-		}
-	}() // Note the parentheses - must call the function.
-
-	// BUFFERED CHANNELS = QUEUES
-	c := make(chan os.Signal, 1) // Queue with a capacity of 1.
-
-	// We'll accept graceful shutdowns when quit via SIGINT (Ctrl+C)
-	// SIGKILL, SIGQUIT or SIGTERM (Ctrl+/) will not be caught.
-	signal.Notify(c, os.Interrupt)
-
-	// Block until we receive our signal.
-	<-c
-
-	// Create a deadline to wait for.
-	ctx, cancel := context.WithTimeout(context.Background(), wait)
-	defer cancel()
-	srv.Shutdown(ctx)
-	fmt.Println("Shutdown web server at " + CurrentLocalTime.String())
-	itrlog.Warn("Server has been shutdown at ", CurrentLocalTime.String())
-	os.Exit(0)
-}
-
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Do stuff here
-		req := "IP:" + sakto.GetIP(r) + ":" + r.RequestURI + ":" + CurrentLocalTime.String()
-		fmt.Println(req)
-		itrlog.Info(req)
-
-		// Call the next handler, which can be another middleware in the chain, or the final handler.
-		next.ServeHTTP(w, r)
-	})
-}
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"gowebapp/api"
+	"gowebapp/config"
+	"gowebapp/pkg/log"
+	"gowebapp/pkg/metrics"
+	"gowebapp/pkg/ratelimit"
+	"gowebapp/pkg/serverset"
+	"gowebapp/yabi"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/gorilla/csrf"
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+	"github.com/itrepablik/sakto"
+	"go.opencensus.io/plugin/ochttp"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// logger is the application-wide structured logger, configured in main from
+// cfg.LogFormat and cfg.LogLevel.
+var logger log.Logger
+
+// CurrentLocalTime holds the server's current local time, computed from
+// cfg.TimeZone once main loads the config.
+var CurrentLocalTime time.Time
+
+func main() {
+	fmt.Println("Hello, WebAssembly!")
+
+	var configPath string
+	var dir string
+	var logFormat string
+	var logLevel string
+	var metricsAddr string
+
+	// dir value for localhost Windows OS must be "static", otherwise, "." for Linux OS
+	flag.StringVar(&configPath, "config", "", "path to the YAML config file; GOWEBAPP_* env vars override its values")
+	flag.StringVar(&dir, "dir", "static", "the directory to serve files from. Defaults to the current dir")
+	flag.StringVar(&logFormat, "log-format", "", "log output format, \"json\" or \"text\"; overrides config/env if set")
+	flag.StringVar(&logLevel, "log-level", "", "log level, e.g. \"debug\", \"info\", \"warn\", \"error\"; overrides config/env if set")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "address the /metrics endpoint listens on; overrides config/env if set")
+	flag.Parse()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Println("failed to load config: ", err)
+		os.Exit(1)
+	}
+	if logFormat != "" {
+		cfg.LogFormat = logFormat
+	}
+	if logLevel != "" {
+		cfg.LogLevel = logLevel
+	}
+	if metricsAddr != "" {
+		cfg.MetricsAddr = metricsAddr
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Setenv("TZ", cfg.TimeZone) // Set the local timezone globally
+	CurrentLocalTime = sakto.GetCurDT(time.Now(), cfg.TimeZone)
+	fmt.Println("Starting the web servers at ", CurrentLocalTime)
+
+	logger = log.New(cfg.LogFormat, cfg.LogLevel)
+	api.SetLogger(logger)
+	yabi.SetLogger(logger)
+
+	r := mux.NewRouter()
+
+	// Create cross-site request forgery (CSRF) protection in every http requests.
+	csrfMiddleware := csrf.Protect(
+		[]byte(cfg.CSRFSecretKey),
+		csrf.Secure(cfg.CSRFSecure),
+		csrf.TrustedOrigins(cfg.CSRFTrustedOrigins),
+	)
+
+	// This is related to the CORS config to allow all origins []string{"*"} or specify only allowed IP or hostname.
+	cors := handlers.CORS(
+		handlers.AllowedHeaders(cfg.CORSAllowedHeaders),
+		handlers.AllowedMethods(cfg.CORSAllowedMethods),
+		handlers.AllowedOrigins(cfg.CORSAllowedOrigins),
+	)
+
+	r.Use(cors)
+	r.Use(csrfMiddleware)
+	r.Use(loggingMiddleware)
+	r.Use(metrics.Middleware)
+	r.Use(mux.CORSMethodMiddleware(r))
+	if cfg.TLSEnabled {
+		r.Use(secureHeadersMiddleware)
+	}
+
+	// Harden the auth routes against brute-forcing before they're registered:
+	// a per-IP token bucket for every request, plus a stricter sliding-window
+	// lockout for repeated login/password-reset failures.
+	authGuard, err := newAuthGuard(cfg, logger)
+	if err != nil {
+		fmt.Println("failed to build auth guard: ", err)
+		os.Exit(1)
+	}
+	r.Use(authGuard.Middleware)
+
+	// This will serve the files under http://localhost:8000/static/<filename>
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(dir))))
+
+	// Initialize the APIs here
+	api.MainRouters(r)      // URLs for the main app.
+	api.AuthRouters(r)      // URLs for the auth app.
+	api.DashboardRouters(r) // URLs for the auth app.
+
+	// Wrap the whole mux with OpenCensus so every request produces a trace
+	// span; the span context flows into yabi's DB calls via r.Context().
+	tracedHandler := &ochttp.Handler{Handler: r}
+
+	// Initialize the Yabi auth API here
+	yabiBaseURL := cfg.BaseURL
+	if yabiBaseURL == "" {
+		yabiBaseURL = "http://" + cfg.ListenAddr + "/" // default to dev localhost
+	}
+	yabi.SetYabiConfig(&yabi.InitYabi{
+		BaseURL:                yabiBaseURL,
+		DBConStr:               cfg.DBDSN,
+		AutoRemoveExpiredToken: 5,
+	})
+
+	// Initializes the http server
+	srv := &http.Server{
+		Addr: cfg.ListenAddr,
+		// Good practice to set timeouts to avoid Slowloris attacks.
+		WriteTimeout: time.Second * 15,
+		ReadTimeout:  time.Second * 15,
+		IdleTimeout:  time.Second * 60,
+		Handler:      tracedHandler, // gorilla/mux wrapped with OpenCensus tracing.
+	}
+
+	// metricsSrv exposes /metrics on its own listener so it isn't subject to
+	// the CORS/CSRF/auth middleware chain above.
+	metricsRouter := mux.NewRouter()
+	metricsRouter.Handle("/metrics", metrics.Handler())
+	metricsSrv := &http.Server{
+		Addr:         cfg.MetricsAddr,
+		Handler:      metricsRouter,
+		ReadTimeout:  time.Second * 15,
+		WriteTimeout: time.Second * 15,
+	}
+
+	// redirectSrv only runs with TLS enabled, it terminates ACME HTTP-01
+	// challenges and redirects everything else to HTTPS.
+	var redirectSrv *http.Server
+	if cfg.TLSEnabled {
+		certManager := newAutocertManager(cfg.CertCacheDir, cfg.Domains)
+		srv.Addr = ":443"
+		srv.TLSConfig = certManager.TLSConfig()
+
+		redirectSrv = &http.Server{
+			Addr:         ":80",
+			Handler:      certManager.HTTPHandler(http.HandlerFunc(redirectToHTTPS)),
+			ReadTimeout:  time.Second * 15,
+			WriteTimeout: time.Second * 15,
+		}
+	}
+
+	// Initialize the MySQL server connection
+	// Open the MySQL DSB Connection
+	dbYabi, err := sql.Open("mysql", cfg.DBDSN)
+	if err != nil {
+		logger.Error(err)
+	}
+	defer dbYabi.Close()
+
+	msg := `Web server started at `
+	fmt.Println(msg, CurrentLocalTime)
+	logger.Info("Web server started at ", CurrentLocalTime)
+
+	yabi.RestoreToken(dbYabi, cfg.TokenEncryptionKey) // Restore the active yabi tokens
+
+	// Register every listener with the server group so they share one
+	// lifecycle: they all start here and all shut down together below,
+	// whether triggered by a signal or by one of them failing to serve.
+	servers := serverset.New()
+	if cfg.TLSEnabled {
+		servers.AddTLS(srv)
+	} else {
+		servers.Add(srv)
+	}
+	if redirectSrv != nil {
+		servers.Add(redirectSrv)
+	}
+	servers.Add(metricsSrv)
+
+	// We'll accept graceful shutdowns when quit via SIGINT (Ctrl+C) or
+	// SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := servers.Run(ctx, cfg.GracefulTimeout); err != nil {
+		logger.Error(err)
+	}
+
+	fmt.Println("Shutdown web server at " + CurrentLocalTime.String())
+	logger.Warn("Server has been shutdown at ", CurrentLocalTime.String())
+	os.Exit(0)
+}
+
+// newAutocertManager builds an autocert.Manager that caches issued certificates
+// on disk under cacheDir and is restricted to the given hosts.
+func newAutocertManager(cacheDir string, hosts []string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+}
+
+// newAuthGuard builds the rate limiting/brute-force guard from cfg, using
+// Redis when cfg.RateLimitRedisAddr is set so the lockout is shared across
+// nodes, and an in-memory store otherwise.
+//
+// FailureMethods/FailureStatusCodes are set explicitly here (from
+// cfg.LoginFailureMethods/cfg.LoginFailureStatusCodes) rather than left to a
+// library default, per yabi's auth routes: they accept POST only, and return
+// 401 on invalid credentials.
+func newAuthGuard(cfg *config.Server, logger log.Logger) (*ratelimit.Guard, error) {
+	var store ratelimit.RateStore
+	if cfg.RateLimitRedisAddr != "" {
+		store = ratelimit.NewRedisStore(redis.NewClient(&redis.Options{
+			Addr: cfg.RateLimitRedisAddr,
+		}), "gowebapp:ratelimit:")
+	} else {
+		store = ratelimit.NewMemoryStore(10000)
+	}
+
+	return ratelimit.NewGuard(ratelimit.Limits{
+		RequestsPerSecond:  cfg.RateLimitRPS,
+		Burst:              cfg.RateLimitBurst,
+		MaxLimiterKeys:     10000,
+		MaxLoginFailures:   cfg.LoginMaxFailures,
+		Window:             cfg.LoginWindow,
+		Lockout:            cfg.LoginLockout,
+		AuthPaths:          cfg.LoginPaths,
+		ExemptPrefixes:     []string{"/static/"},
+		FailureMethods:     cfg.LoginFailureMethods,
+		FailureStatusCodes: cfg.LoginFailureStatusCodes,
+	}, store, logger)
+}
+
+// redirectToHTTPS sends every non-ACME request on :80 to its HTTPS equivalent.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	target := "https://" + r.Host + r.URL.RequestURI()
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// secureHeadersMiddleware sets the baseline security headers expected in
+// production: HSTS, frame/content-type sniffing protection, and a CSP.
+func secureHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder wraps an http.ResponseWriter so loggingMiddleware can
+// capture the status code written by downstream handlers.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(log.RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(log.RequestIDHeader, requestID)
+		r = r.WithContext(log.WithRequestID(r.Context(), requestID))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		// Call the next handler, which can be another middleware in the chain, or the final handler.
+		next.ServeHTTP(rec, r)
+
+		logger.WithFields(log.Fields{
+			"request_id": requestID,
+			"method":     r.Method,
+			"path":       r.RequestURI,
+			"status":     rec.status,
+			"duration":   time.Since(start).String(),
+			"remote_ip":  sakto.GetIP(r),
+		}).Info("request completed")
+	})
+}
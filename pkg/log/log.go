@@ -0,0 +1,87 @@
+// Package log provides a small, pluggable structured logging interface used
+// across gowebapp so call sites don't depend on a concrete logging library.
+// It is backed by logrus today but can be swapped for zap without touching
+// callers.
+package log
+
+import (
+	"context"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ctxKey is an unexported type to avoid collisions with other packages'
+// context keys.
+type ctxKey int
+
+// requestIDKey is the context key under which the current request's
+// correlation ID is stored.
+const requestIDKey ctxKey = iota
+
+// RequestIDHeader is the HTTP header used to propagate the correlation ID
+// between the client and the server, and across downstream calls.
+const RequestIDHeader = "X-Request-ID"
+
+// Logger is the structured logging interface used by gowebapp. Fields is the
+// unit of structured context, e.g. Fields{"status": 200}.
+type Logger interface {
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+	WithFields(fields Fields) Logger
+}
+
+// Fields is a set of structured key/value pairs attached to a log entry.
+type Fields map[string]interface{}
+
+// logrusLogger adapts *logrus.Entry to the Logger interface.
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// New builds the default Logger, formatted as "json" or "text" and filtered
+// at the given level ("debug", "info", "warn", "error").
+func New(format, level string) Logger {
+	l := logrus.New()
+
+	if format == "json" {
+		l.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		l.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	}
+
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		lvl = logrus.InfoLevel
+	}
+	l.SetLevel(lvl)
+
+	return &logrusLogger{entry: logrus.NewEntry(l)}
+}
+
+// NewNop returns a Logger that discards everything, for use in tests.
+func NewNop() Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return &logrusLogger{entry: logrus.NewEntry(l)}
+}
+
+func (l *logrusLogger) Info(args ...interface{})  { l.entry.Info(args...) }
+func (l *logrusLogger) Warn(args ...interface{})  { l.entry.Warn(args...) }
+func (l *logrusLogger) Error(args ...interface{}) { l.entry.Error(args...) }
+
+func (l *logrusLogger) WithFields(fields Fields) Logger {
+	return &logrusLogger{entry: l.entry.WithFields(logrus.Fields(fields))}
+}
+
+// WithRequestID returns a context carrying the given correlation ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the correlation ID stored on ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
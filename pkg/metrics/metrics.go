@@ -0,0 +1,119 @@
+// Package metrics exposes the Prometheus collectors and middleware used to
+// instrument gowebapp's HTTP layer.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gowebapp_http_requests_total",
+			Help: "Total number of HTTP requests handled, labeled by method, route and status.",
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	requestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gowebapp_http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served, labeled by route.",
+		},
+		[]string{"route"},
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gowebapp_http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds, labeled by method, route and status.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	responseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gowebapp_http_response_size_bytes",
+			Help:    "HTTP response size in bytes, labeled by method, route and status.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"method", "route", "status"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, requestsInFlight, requestDuration, responseSize)
+}
+
+// Handler returns the HTTP handler to mount at /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// recordingWriter wraps an http.ResponseWriter to capture the status code
+// and number of bytes written, for the histograms above.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Middleware records per-route request counts, in-flight requests, response
+// sizes, and latency. It reads the route template from mux.CurrentRoute, so
+// it must run as mux router middleware (r.Use), which mux applies only after
+// a request has been matched to a route.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		requestsInFlight.WithLabelValues(route).Inc()
+		defer requestsInFlight.WithLabelValues(route).Dec()
+
+		start := time.Now()
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		status := strconv.Itoa(rw.status)
+		duration := time.Since(start).Seconds()
+
+		requestsTotal.WithLabelValues(r.Method, route, status).Inc()
+		requestDuration.WithLabelValues(r.Method, route, status).Observe(duration)
+		responseSize.WithLabelValues(r.Method, route, status).Observe(float64(rw.bytes))
+	})
+}
+
+// unmatchedRoute is the route label used for requests mux couldn't match to
+// a registered route template (e.g. 404s). Using the raw request path
+// instead would let an attacker hitting random URLs create unbounded label
+// cardinality on the metrics below.
+const unmatchedRoute = "<unmatched>"
+
+// routeTemplate returns the matched mux route's path template, or
+// unmatchedRoute if the request didn't match a registered route.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return unmatchedRoute
+}
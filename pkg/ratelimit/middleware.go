@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gowebapp/pkg/log"
+
+	"github.com/itrepablik/sakto"
+	"golang.org/x/time/rate"
+)
+
+// Limits configures the per-IP token bucket and the stricter login-failure
+// lockout applied on top of it.
+type Limits struct {
+	// RequestsPerSecond and Burst bound the general token-bucket limiter
+	// applied to every request, per client IP.
+	RequestsPerSecond float64
+	Burst             int
+
+	// MaxLimiterKeys bounds how many distinct client IPs have their own
+	// token-bucket limiter tracked at once; the least-recently-seen IP is
+	// evicted once the limit is exceeded.
+	MaxLimiterKeys int
+
+	// MaxLoginFailures is how many failed attempts against an AuthPath are
+	// tolerated within Window before the IP is locked out for Lockout.
+	MaxLoginFailures int
+	Window           time.Duration
+	Lockout          time.Duration
+
+	// AuthPaths are the request paths the stricter login lockout applies
+	// to, matched exactly (or as a path segment, so "/login" also covers
+	// "/login/"), e.g. "/login" and "/password-reset".
+	AuthPaths []string
+	// ExemptPrefixes are path prefixes (e.g. "/static/") skipped entirely by
+	// both the token bucket and the login lockout, so asset requests can't
+	// trip either one.
+	ExemptPrefixes []string
+	// FailureMethods are the HTTP methods that actually submit credentials
+	// on an AuthPath (a credential check doesn't happen on the GET that
+	// renders the login form, only the POST that submits it). There is no
+	// default: the caller must set this to whatever yabi's auth routes
+	// actually accept.
+	FailureMethods []string
+	// FailureStatusCodes are the response statuses that indicate a failed
+	// credential check on an AuthPath. There is no default: shipping a
+	// guessed value here risks a lockout that silently never triggers, so
+	// NewGuard requires the caller to set this explicitly against whatever
+	// yabi's auth routes actually return on bad credentials.
+	FailureStatusCodes []int
+}
+
+// Guard applies Limits in front of a handler: a token-bucket rate limit for
+// every request, plus a sliding-window lockout for repeated login failures
+// on AuthPaths.
+type Guard struct {
+	limits Limits
+	store  RateStore
+	logger log.Logger
+
+	mu       sync.Mutex
+	limiters map[string]*list.Element
+	order    *list.List // front = most recently used
+}
+
+// limiterEntry is one client IP's token-bucket limiter, LRU-tracked
+// alongside Guard.limiters/order.
+type limiterEntry struct {
+	ip      string
+	limiter *rate.Limiter
+}
+
+// ErrFailureSignalNotConfigured is returned by NewGuard when limits doesn't
+// say which method/status combination marks a failed login. There is no
+// library default for this: it must match whatever the auth routes it's
+// guarding actually do on bad credentials.
+var ErrFailureSignalNotConfigured = errors.New("ratelimit: Limits.FailureMethods and Limits.FailureStatusCodes must be set explicitly")
+
+// NewGuard builds a Guard backed by store (MemoryStore for single-node,
+// RedisStore for multi-node deployments). It returns
+// ErrFailureSignalNotConfigured if limits doesn't specify FailureMethods and
+// FailureStatusCodes, since guessing wrong here means the lockout silently
+// never triggers.
+func NewGuard(limits Limits, store RateStore, logger log.Logger) (*Guard, error) {
+	if len(limits.FailureMethods) == 0 || len(limits.FailureStatusCodes) == 0 {
+		return nil, ErrFailureSignalNotConfigured
+	}
+	return &Guard{
+		limits:   limits,
+		store:    store,
+		logger:   logger,
+		limiters: make(map[string]*list.Element),
+		order:    list.New(),
+	}, nil
+}
+
+// Middleware enforces the token-bucket and login-lockout limits before
+// calling next.
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.isExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := sakto.GetIP(r)
+
+		if !g.limiterFor(ip).Allow() {
+			g.tooManyRequests(w, ip, r.URL.Path, time.Second)
+			return
+		}
+
+		if !g.isAuthPath(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if locked, remaining, err := g.store.Locked(r.Context(), ip); err != nil {
+			g.logger.Error("ratelimit: failed to read lockout state: ", err)
+		} else if locked {
+			g.tooManyRequests(w, ip, r.URL.Path, remaining)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if !g.isCredentialCheck(r) {
+			return
+		}
+
+		if !g.isFailureStatus(rec.status) {
+			g.store.Reset(r.Context(), ip)
+			return
+		}
+
+		count, err := g.store.Fail(r.Context(), ip, g.limits.Window)
+		if err != nil {
+			g.logger.Error("ratelimit: failed to record login failure: ", err)
+			return
+		}
+
+		fields := log.Fields{
+			"remote_ip": ip,
+			"path":      r.URL.Path,
+			"failures":  count,
+		}
+		if count >= g.limits.MaxLoginFailures {
+			if err := g.store.Lock(r.Context(), ip, g.limits.Lockout); err != nil {
+				g.logger.Error("ratelimit: failed to set lockout: ", err)
+			}
+			g.logger.WithFields(fields).Warn("security: login lockout triggered")
+			return
+		}
+		g.logger.WithFields(fields).Warn("security: failed login attempt")
+	})
+}
+
+// limiterFor returns the token-bucket limiter for ip, creating one on first
+// use and evicting the least-recently-used IP once MaxLimiterKeys is
+// exceeded.
+func (g *Guard) limiterFor(ip string) *rate.Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if el, ok := g.limiters[ip]; ok {
+		g.order.MoveToFront(el)
+		return el.Value.(*limiterEntry).limiter
+	}
+
+	entry := &limiterEntry{ip: ip, limiter: rate.NewLimiter(rate.Limit(g.limits.RequestsPerSecond), g.limits.Burst)}
+	g.limiters[ip] = g.order.PushFront(entry)
+
+	if g.limits.MaxLimiterKeys > 0 {
+		for len(g.limiters) > g.limits.MaxLimiterKeys {
+			oldest := g.order.Back()
+			if oldest == nil {
+				break
+			}
+			g.order.Remove(oldest)
+			delete(g.limiters, oldest.Value.(*limiterEntry).ip)
+		}
+	}
+
+	return entry.limiter
+}
+
+// isAuthPath reports whether r targets one of the stricter login/reset
+// routes configured in g.limits.AuthPaths. A path matches only exactly or as
+// a "/"-bounded segment, so "/login" doesn't also match "/login-history".
+func (g *Guard) isAuthPath(r *http.Request) bool {
+	path := r.URL.Path
+	for _, p := range g.limits.AuthPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isExempt reports whether r falls under one of g.limits.ExemptPrefixes and
+// should bypass both the token bucket and the login lockout entirely, e.g.
+// static asset requests.
+func (g *Guard) isExempt(r *http.Request) bool {
+	for _, p := range g.limits.ExemptPrefixes {
+		if strings.HasPrefix(r.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCredentialCheck reports whether r's method is one that actually submits
+// credentials, as opposed to e.g. the GET that renders the login form.
+func (g *Guard) isCredentialCheck(r *http.Request) bool {
+	for _, m := range g.limits.FailureMethods {
+		if strings.EqualFold(r.Method, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFailureStatus reports whether status indicates a failed credential
+// check, per g.limits.FailureStatusCodes.
+func (g *Guard) isFailureStatus(status int) bool {
+	for _, s := range g.limits.FailureStatusCodes {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Guard) tooManyRequests(w http.ResponseWriter, ip, path string, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	w.WriteHeader(http.StatusTooManyRequests)
+	g.logger.WithFields(log.Fields{
+		"remote_ip": ip,
+		"path":      path,
+	}).Warn("security: rate limit exceeded")
+}
+
+// statusRecorder captures the status code a downstream handler wrote, so
+// Middleware can tell whether a login attempt failed.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gowebapp/pkg/log"
+)
+
+func TestNewGuardRequiresExplicitFailureSignal(t *testing.T) {
+	store := NewMemoryStore(10)
+
+	if _, err := NewGuard(Limits{}, store, log.NewNop()); err != ErrFailureSignalNotConfigured {
+		t.Fatalf("err = %v, want ErrFailureSignalNotConfigured", err)
+	}
+
+	_, err := NewGuard(Limits{
+		FailureMethods:     []string{http.MethodPost},
+		FailureStatusCodes: []int{http.StatusUnauthorized},
+	}, store, log.NewNop())
+	if err != nil {
+		t.Fatalf("NewGuard with explicit failure signal: %v", err)
+	}
+}
+
+func newTestGuard(t *testing.T) *Guard {
+	t.Helper()
+	g, err := NewGuard(Limits{
+		RequestsPerSecond:  1000,
+		Burst:              1000,
+		MaxLimiterKeys:     100,
+		MaxLoginFailures:   2,
+		Window:             time.Minute,
+		Lockout:            time.Minute,
+		AuthPaths:          []string{"/login"},
+		ExemptPrefixes:     []string{"/static/"},
+		FailureMethods:     []string{http.MethodPost},
+		FailureStatusCodes: []int{http.StatusUnauthorized},
+	}, NewMemoryStore(100), log.NewNop())
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+	return g
+}
+
+func TestGuardIsAuthPathExactOrSubpathMatch(t *testing.T) {
+	g := newTestGuard(t)
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/login", true},
+		{"/login/", true},
+		{"/login-history", false},
+		{"/loginfoobar", false},
+		{"/dashboard", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if got := g.isAuthPath(r); got != c.want {
+			t.Errorf("isAuthPath(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestGuardLockoutAfterMaxFailures(t *testing.T) {
+	g := newTestGuard(t)
+
+	fail := func() int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := fail(); code != http.StatusUnauthorized {
+		t.Fatalf("first failed login status = %d, want 401", code)
+	}
+	if code := fail(); code != http.StatusUnauthorized {
+		t.Fatalf("second failed login status = %d, want 401", code)
+	}
+
+	// MaxLoginFailures is 2, so the third attempt should already be locked
+	// out and never reach the handler.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not run once locked out")
+	})).ServeHTTP(w, r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status after lockout = %d, want 429", w.Code)
+	}
+}
+
+func TestGuardExemptPrefixSkipsLimiterAndLockout(t *testing.T) {
+	g := newTestGuard(t)
+
+	called := false
+	handler := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("static asset request %d status = %d, want 200", i, w.Code)
+		}
+	}
+	if !called {
+		t.Fatal("expected exempt request to reach the handler")
+	}
+}
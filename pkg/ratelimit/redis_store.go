@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStore is a RateStore backed by Redis, for multi-node deployments
+// where failure counts and lockouts must be shared across instances. Each
+// key's failure history is a sorted set scored by attempt time so Fail can
+// prune it into a true sliding window; the lockout is a separate key with
+// its own TTL.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore that namespaces its keys under prefix
+// (e.g. "gowebapp:ratelimit:") to avoid colliding with other Redis users.
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) failuresKey(key string) string { return s.prefix + "fail:" + key }
+func (s *RedisStore) lockKey(key string) string     { return s.prefix + "lock:" + key }
+
+func (s *RedisStore) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	redisKey := s.failuresKey(key)
+	now := time.Now()
+	cutoff := now.Add(-window)
+
+	pipe := s.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, redisKey, "-inf", strconv.FormatInt(cutoff.UnixNano(), 10))
+	pipe.ZAdd(ctx, redisKey, &redis.Z{Score: float64(now.UnixNano()), Member: now.UnixNano()})
+	pipe.Expire(ctx, redisKey, window)
+	card := pipe.ZCard(ctx, redisKey)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(card.Val()), nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, duration time.Duration) error {
+	return s.client.Set(ctx, s.lockKey(key), "1", duration).Err()
+}
+
+func (s *RedisStore) Locked(ctx context.Context, key string) (bool, time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, s.lockKey(key)).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if ttl <= 0 {
+		return false, 0, nil
+	}
+	return true, ttl, nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.failuresKey(key), s.lockKey(key)).Err()
+}
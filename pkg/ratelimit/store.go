@@ -0,0 +1,143 @@
+// Package ratelimit provides the IP-based rate limiting and brute-force
+// lockout middleware used in front of gowebapp's auth routes.
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// RateStore tracks failed-login attempts and lockouts for brute-force
+// protection, keyed by client IP. Implementations must be safe for
+// concurrent use.
+type RateStore interface {
+	// Fail records a failed attempt for key and returns how many failures
+	// have landed within the trailing window (a sliding window: each call
+	// prunes attempts older than window before counting).
+	Fail(ctx context.Context, key string, window time.Duration) (count int, err error)
+	// Lock marks key as locked out for duration, independent of the
+	// sliding failure window tracked by Fail.
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	// Locked reports whether key is currently locked out and, if so, how
+	// much longer the lockout has left.
+	Locked(ctx context.Context, key string) (locked bool, remaining time.Duration, err error)
+	// Reset clears both the failure history and any lockout for key, e.g.
+	// once a login succeeds.
+	Reset(ctx context.Context, key string) error
+}
+
+// memoryEntry is one key's tracked failure history and lockout state.
+type memoryEntry struct {
+	key         string
+	failures    []time.Time
+	lockedUntil time.Time
+}
+
+// MemoryStore is an in-memory RateStore for single-node deployments. It
+// bounds its own size with LRU eviction so an attacker spraying IPs can't
+// grow it without limit.
+type MemoryStore struct {
+	mu      sync.Mutex
+	maxKeys int
+	entries map[string]*list.Element
+	order   *list.List // front = most recently used
+}
+
+// NewMemoryStore returns a MemoryStore that evicts its least-recently-used
+// key once more than maxKeys distinct keys are being tracked.
+func NewMemoryStore(maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (s *MemoryStore) Fail(_ context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry := s.getOrCreate(key)
+	entry.failures = append(entry.failures, now)
+	entry.failures = pruneBefore(entry.failures, now.Add(-window))
+	return len(entry.failures), nil
+}
+
+func (s *MemoryStore) Lock(_ context.Context, key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.getOrCreate(key).lockedUntil = time.Now().Add(duration)
+	return nil
+}
+
+func (s *MemoryStore) Locked(_ context.Context, key string) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return false, 0, nil
+	}
+	remaining := el.Value.(*memoryEntry).lockedUntil.Sub(time.Now())
+	if remaining <= 0 {
+		return false, 0, nil
+	}
+	return true, remaining, nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// getOrCreate returns key's entry, creating and LRU-registering one if
+// needed. Callers must hold s.mu.
+func (s *MemoryStore) getOrCreate(key string) *memoryEntry {
+	if el, ok := s.entries[key]; ok {
+		s.order.MoveToFront(el)
+		return el.Value.(*memoryEntry)
+	}
+
+	entry := &memoryEntry{key: key}
+	s.entries[key] = s.order.PushFront(entry)
+	s.evictIfNeeded()
+	return entry
+}
+
+// evictIfNeeded drops the least-recently-used key once the store holds more
+// than maxKeys entries. Callers must hold s.mu.
+func (s *MemoryStore) evictIfNeeded() {
+	if s.maxKeys <= 0 {
+		return
+	}
+	for len(s.entries) > s.maxKeys {
+		oldest := s.order.Back()
+		if oldest == nil {
+			return
+		}
+		entry := oldest.Value.(*memoryEntry)
+		s.order.Remove(oldest)
+		delete(s.entries, entry.key)
+	}
+}
+
+// pruneBefore drops every timestamp at or before cutoff, preserving order.
+func pruneBefore(timestamps []time.Time, cutoff time.Time) []time.Time {
+	kept := timestamps[:0]
+	for _, t := range timestamps {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSlidingWindowPrunesOldFailures(t *testing.T) {
+	s := NewMemoryStore(10)
+	ctx := context.Background()
+
+	count, err := s.Fail(ctx, "1.2.3.4", time.Minute)
+	if err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+
+	// A failure recorded outside the window shouldn't count toward the next
+	// Fail's total.
+	el := s.entries["1.2.3.4"]
+	el.Value.(*memoryEntry).failures[0] = time.Now().Add(-2 * time.Minute)
+
+	count, err = s.Fail(ctx, "1.2.3.4", time.Minute)
+	if err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count after stale entry pruned = %d, want 1", count)
+	}
+}
+
+func TestMemoryStoreLRUEviction(t *testing.T) {
+	s := NewMemoryStore(2)
+	ctx := context.Background()
+
+	s.Fail(ctx, "a", time.Minute)
+	s.Fail(ctx, "b", time.Minute)
+	s.Fail(ctx, "a", time.Minute) // touch "a" so "b" becomes least-recently-used
+	s.Fail(ctx, "c", time.Minute) // should evict "b", not "a"
+
+	if _, ok := s.entries["b"]; ok {
+		t.Fatal("expected least-recently-used key \"b\" to be evicted")
+	}
+	if _, ok := s.entries["a"]; !ok {
+		t.Fatal("expected recently-touched key \"a\" to survive eviction")
+	}
+	if len(s.entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(s.entries))
+	}
+}
+
+func TestMemoryStoreLockExpires(t *testing.T) {
+	s := NewMemoryStore(10)
+	ctx := context.Background()
+
+	if err := s.Lock(ctx, "1.2.3.4", 10*time.Millisecond); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	locked, remaining, err := s.Locked(ctx, "1.2.3.4")
+	if err != nil {
+		t.Fatalf("Locked: %v", err)
+	}
+	if !locked || remaining <= 0 {
+		t.Fatalf("Locked = (%v, %v), want locked with positive remaining", locked, remaining)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	locked, _, err = s.Locked(ctx, "1.2.3.4")
+	if err != nil {
+		t.Fatalf("Locked: %v", err)
+	}
+	if locked {
+		t.Fatal("expected lockout to have expired")
+	}
+}
+
+func TestMemoryStoreReset(t *testing.T) {
+	s := NewMemoryStore(10)
+	ctx := context.Background()
+
+	s.Fail(ctx, "1.2.3.4", time.Minute)
+	s.Lock(ctx, "1.2.3.4", time.Minute)
+
+	if err := s.Reset(ctx, "1.2.3.4"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	if _, ok := s.entries["1.2.3.4"]; ok {
+		t.Fatal("expected Reset to remove the entry entirely")
+	}
+	locked, _, err := s.Locked(ctx, "1.2.3.4")
+	if err != nil {
+		t.Fatalf("Locked: %v", err)
+	}
+	if locked {
+		t.Fatal("expected no lockout after Reset")
+	}
+}
@@ -0,0 +1,76 @@
+// Package serverset runs a group of http.Server instances concurrently and
+// shuts them all down together, either because the caller canceled the
+// context or because one of the servers failed to serve.
+package serverset
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Group is a set of http.Server instances that start and stop as a unit,
+// e.g. the public HTTPS listener, its HTTP->HTTPS redirect, and the metrics
+// listener.
+type Group struct {
+	servers []*http.Server
+	tls     []bool // parallel to servers: whether that server should use ListenAndServeTLS
+}
+
+// New returns an empty Group.
+func New() *Group {
+	return &Group{}
+}
+
+// Add registers srv to run via ListenAndServe when the group starts.
+func (g *Group) Add(srv *http.Server) {
+	g.servers = append(g.servers, srv)
+	g.tls = append(g.tls, false)
+}
+
+// AddTLS registers srv to run via ListenAndServeTLS when the group starts.
+// srv.TLSConfig must already supply the certificates, e.g. via autocert.
+func (g *Group) AddTLS(srv *http.Server) {
+	g.servers = append(g.servers, srv)
+	g.tls = append(g.tls, true)
+}
+
+// Run starts every registered server concurrently and blocks until ctx is
+// canceled or any server's ListenAndServe(TLS) returns a fatal error. Either
+// way, every server is given gracefulTimeout to shut down before Run
+// returns the first fatal error encountered, if any.
+func (g *Group) Run(ctx context.Context, gracefulTimeout time.Duration) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	for i, srv := range g.servers {
+		srv := srv
+		useTLS := g.tls[i]
+		eg.Go(func() error {
+			var err error
+			if useTLS {
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
+	}
+
+	eg.Go(func() error {
+		<-egCtx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
+		defer cancel()
+		for _, srv := range g.servers {
+			srv.Shutdown(shutdownCtx)
+		}
+		return nil
+	})
+
+	return eg.Wait()
+}
@@ -0,0 +1,77 @@
+package serverset
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// listenOnFreePort returns an address on the loopback interface that's free
+// at the time of the call, for servers to bind to in tests.
+func listenOnFreePort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestGroupRunStopsAllServersOnContextCancel(t *testing.T) {
+	g := New()
+	srv1 := &http.Server{Addr: listenOnFreePort(t)}
+	srv2 := &http.Server{Addr: listenOnFreePort(t)}
+	g.Add(srv1)
+	g.Add(srv2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- g.Run(ctx, time.Second) }()
+
+	// Give the servers a moment to start listening before asking them to
+	// stop.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v, want nil on graceful shutdown", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+func TestGroupRunReturnsFatalServerError(t *testing.T) {
+	g := New()
+	// The server's address is already held by a plain listener, so its
+	// ListenAndServe call fails immediately, which Run should surface.
+	addr := listenOnFreePort(t)
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	srv := &http.Server{Addr: addr}
+	g.Add(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = g.Run(ctx, time.Second)
+	if err == nil {
+		t.Fatal("expected Run to return an error when the listener address is already in use")
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("err = %v, want a net.OpError (address in use)", err)
+	}
+}
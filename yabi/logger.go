@@ -0,0 +1,14 @@
+package yabi
+
+import "gowebapp/pkg/log"
+
+// logger is the structured logger used by this package's auth/token
+// handlers. It defaults to a no-op logger so the package is usable (and
+// testable) before SetLogger is called.
+var logger log.Logger = log.NewNop()
+
+// SetLogger injects the application's logger into this package, so tests
+// can supply a no-op logger instead of depending on a package-level global.
+func SetLogger(l log.Logger) {
+	logger = l
+}